@@ -0,0 +1,352 @@
+// Package cmap provides a thread-safe map keyed by any comparable type,
+// sharded across a fixed number of buckets to reduce lock contention
+// between goroutines operating on unrelated keys.
+package cmap
+
+import (
+	"encoding/json"
+	"reflect"
+	"sync"
+)
+
+// SHARD_COUNT is the number of shards backing every ConcurrentMap. It must
+// be set before a map is created; changing it afterwards has no effect on
+// maps already constructed.
+var SHARD_COUNT = 32
+
+// Sharder assigns a key to a shard bucket. It need not spread evenly over
+// the whole uint32 range: GetShard reduces the result modulo SHARD_COUNT.
+type Sharder[K comparable] func(key K) uint32
+
+// ConcurrentMap is a thread safe map of type K to V, sharded across
+// several underlying maps to lower lock contention.
+type ConcurrentMap[K comparable, V any] struct {
+	shards   []*ConcurrentMapShared[K, V]
+	sharding Sharder[K]
+	equal    Equal[V]
+}
+
+// Equal reports whether a and b should be considered equal, used by
+// CompareAndSwap and CompareAndDelete.
+type Equal[V any] func(a, b V) bool
+
+// WithEqual returns a copy of m that compares values with equal instead of
+// the reflect.DeepEqual fallback, for use by CompareAndSwap and
+// CompareAndDelete.
+func (m ConcurrentMap[K, V]) WithEqual(equal Equal[V]) ConcurrentMap[K, V] {
+	m.equal = equal
+	return m
+}
+
+func (m ConcurrentMap[K, V]) equalFunc() Equal[V] {
+	if m.equal != nil {
+		return m.equal
+	}
+	return func(a, b V) bool { return reflect.DeepEqual(a, b) }
+}
+
+// ConcurrentMapShared is a single shard of a ConcurrentMap, guarded by its
+// own lock.
+type ConcurrentMapShared[K comparable, V any] struct {
+	items map[K]V
+	sync.RWMutex
+}
+
+func create[K comparable, V any](sharding Sharder[K]) ConcurrentMap[K, V] {
+	m := ConcurrentMap[K, V]{
+		sharding: sharding,
+		shards:   make([]*ConcurrentMapShared[K, V], SHARD_COUNT),
+	}
+	for i := 0; i < SHARD_COUNT; i++ {
+		m.shards[i] = &ConcurrentMapShared[K, V]{items: make(map[K]V)}
+	}
+	return m
+}
+
+// New creates a ConcurrentMap keyed by uint16 holding untyped values,
+// exactly as the pre-generics New() always did, so existing call sites
+// keep compiling unmodified. Use NewTyped for a map parameterized over a
+// specific value type, and NewWithCustomShardingFunction for any other
+// key type.
+func New() ConcurrentMap[uint16, interface{}] {
+	return NewTyped[interface{}]()
+}
+
+// NewTyped creates a ConcurrentMap keyed by uint16, matching the sharding
+// this package has always used, parameterized over the given value type.
+// Use NewWithCustomShardingFunction for any other key type.
+func NewTyped[V any]() ConcurrentMap[uint16, V] {
+	return create[uint16, V](ShardUint16)
+}
+
+// NewWithCustomShardingFunction creates a ConcurrentMap over an arbitrary
+// comparable key type K, assigning keys to shards via sharding.
+func NewWithCustomShardingFunction[K comparable, V any](sharding Sharder[K]) ConcurrentMap[K, V] {
+	return create[K, V](sharding)
+}
+
+// GetShard returns the shard responsible for key.
+func (m ConcurrentMap[K, V]) GetShard(key K) *ConcurrentMapShared[K, V] {
+	return m.shards[uint(m.sharding(key))%uint(len(m.shards))]
+}
+
+// MSet sets the given key/value pairs in a single pass.
+func (m ConcurrentMap[K, V]) MSet(data map[K]V) {
+	for key, value := range data {
+		shard := m.GetShard(key)
+		shard.Lock()
+		shard.items[key] = value
+		shard.Unlock()
+	}
+}
+
+// Set sets the given value under the given key.
+func (m ConcurrentMap[K, V]) Set(key K, value V) {
+	shard := m.GetShard(key)
+	shard.Lock()
+	shard.items[key] = value
+	shard.Unlock()
+}
+
+// SetIfAbsent sets the given value under the given key if no value was
+// already associated with it.
+func (m ConcurrentMap[K, V]) SetIfAbsent(key K, value V) bool {
+	shard := m.GetShard(key)
+	shard.Lock()
+	_, ok := shard.items[key]
+	if !ok {
+		shard.items[key] = value
+	}
+	shard.Unlock()
+	return !ok
+}
+
+// LoadOrStore returns the existing value for key if present; otherwise it
+// stores and returns value.
+func (m ConcurrentMap[K, V]) LoadOrStore(key K, value V) (actual V, loaded bool) {
+	shard := m.GetShard(key)
+	shard.Lock()
+	actual, loaded = shard.items[key]
+	if !loaded {
+		actual = value
+		shard.items[key] = value
+	}
+	shard.Unlock()
+	return actual, loaded
+}
+
+// Swap stores value for key, returning the previous value if any.
+func (m ConcurrentMap[K, V]) Swap(key K, value V) (previous V, loaded bool) {
+	shard := m.GetShard(key)
+	shard.Lock()
+	previous, loaded = shard.items[key]
+	shard.items[key] = value
+	shard.Unlock()
+	return previous, loaded
+}
+
+// CompareAndSwap stores new for key only if its current value compares
+// equal to old, per m's Equal function (reflect.DeepEqual by default; see
+// WithEqual).
+func (m ConcurrentMap[K, V]) CompareAndSwap(key K, old, new V) (swapped bool) {
+	equal := m.equalFunc()
+	shard := m.GetShard(key)
+	shard.Lock()
+	defer shard.Unlock()
+	cur, ok := shard.items[key]
+	if !ok || !equal(cur, old) {
+		return false
+	}
+	shard.items[key] = new
+	return true
+}
+
+// CompareAndDelete deletes the entry for key only if its current value
+// compares equal to old, per m's Equal function (reflect.DeepEqual by
+// default; see WithEqual).
+func (m ConcurrentMap[K, V]) CompareAndDelete(key K, old V) (deleted bool) {
+	equal := m.equalFunc()
+	shard := m.GetShard(key)
+	shard.Lock()
+	defer shard.Unlock()
+	cur, ok := shard.items[key]
+	if !ok || !equal(cur, old) {
+		return false
+	}
+	delete(shard.items, key)
+	return true
+}
+
+// UpsertCb is called by Upsert with whether key already had a value, that
+// value (the zero value of V if not), and the newly supplied value; it
+// must return the value to store.
+type UpsertCb[V any] func(exist bool, valueInMap V, newValue V) V
+
+// Upsert inserts or updates a key/value pair under the shard's lock,
+// letting cb decide the resulting value from the old and new ones.
+func (m ConcurrentMap[K, V]) Upsert(key K, value V, cb UpsertCb[V]) (res V) {
+	shard := m.GetShard(key)
+	shard.Lock()
+	v, ok := shard.items[key]
+	res = cb(ok, v, value)
+	shard.items[key] = res
+	shard.Unlock()
+	return res
+}
+
+// Get retrieves the value associated with key.
+func (m ConcurrentMap[K, V]) Get(key K) (V, bool) {
+	shard := m.GetShard(key)
+	shard.RLock()
+	val, ok := shard.items[key]
+	shard.RUnlock()
+	return val, ok
+}
+
+// Count returns the number of elements within the map.
+func (m ConcurrentMap[K, V]) Count() int {
+	count := 0
+	for _, shard := range m.shards {
+		shard.RLock()
+		count += len(shard.items)
+		shard.RUnlock()
+	}
+	return count
+}
+
+// Has looks up an item under the given key, reporting whether it's present.
+func (m ConcurrentMap[K, V]) Has(key K) bool {
+	shard := m.GetShard(key)
+	shard.RLock()
+	_, ok := shard.items[key]
+	shard.RUnlock()
+	return ok
+}
+
+// Remove deletes the given key from the map.
+func (m ConcurrentMap[K, V]) Remove(key K) {
+	shard := m.GetShard(key)
+	shard.Lock()
+	delete(shard.items, key)
+	shard.Unlock()
+}
+
+// Pop removes an element from the map and returns it.
+func (m ConcurrentMap[K, V]) Pop(key K) (v V, exists bool) {
+	shard := m.GetShard(key)
+	shard.Lock()
+	v, exists = shard.items[key]
+	delete(shard.items, key)
+	shard.Unlock()
+	return v, exists
+}
+
+// IsEmpty checks if the map is empty.
+func (m ConcurrentMap[K, V]) IsEmpty() bool {
+	return m.Count() == 0
+}
+
+// Tuple is a key/value pair yielded while iterating a ConcurrentMap.
+type Tuple[K comparable, V any] struct {
+	Key K
+	Val V
+}
+
+// Iter returns an unbuffered channel of key/value pairs. It holds each
+// shard's lock for the lifetime of that shard's portion of the range, so
+// slow consumers block writers to that shard; prefer IterBuffered when that
+// matters.
+func (m ConcurrentMap[K, V]) Iter() <-chan Tuple[K, V] {
+	ch := make(chan Tuple[K, V])
+	go func() {
+		for _, shard := range m.shards {
+			shard.RLock()
+			for key, val := range shard.items {
+				ch <- Tuple[K, V]{key, val}
+			}
+			shard.RUnlock()
+		}
+		close(ch)
+	}()
+	return ch
+}
+
+// IterBuffered returns a buffered channel of key/value pairs, sized to the
+// map's count at call time so producers never block on consumers.
+func (m ConcurrentMap[K, V]) IterBuffered() <-chan Tuple[K, V] {
+	ch := make(chan Tuple[K, V], m.Count())
+	go func() {
+		wg := sync.WaitGroup{}
+		wg.Add(len(m.shards))
+		for _, shard := range m.shards {
+			go func(shard *ConcurrentMapShared[K, V]) {
+				shard.RLock()
+				for key, val := range shard.items {
+					ch <- Tuple[K, V]{key, val}
+				}
+				shard.RUnlock()
+				wg.Done()
+			}(shard)
+		}
+		wg.Wait()
+		close(ch)
+	}()
+	return ch
+}
+
+// Items returns all items as a plain map.
+func (m ConcurrentMap[K, V]) Items() map[K]V {
+	tmp := make(map[K]V)
+	for item := range m.IterBuffered() {
+		tmp[item.Key] = item.Val
+	}
+	return tmp
+}
+
+// IterCb is called once per entry by IterCb.
+type IterCb[K comparable, V any] func(key K, v V)
+
+// IterCb iterates the map calling fn for each key/value pair, holding each
+// shard's read lock for the duration of that shard's callbacks.
+func (m ConcurrentMap[K, V]) IterCb(fn IterCb[K, V]) {
+	for _, shard := range m.shards {
+		shard.RLock()
+		for key, value := range shard.items {
+			fn(key, value)
+		}
+		shard.RUnlock()
+	}
+}
+
+// Keys returns all keys as a slice.
+func (m ConcurrentMap[K, V]) Keys() []K {
+	count := m.Count()
+	ch := make(chan K, count)
+	go func() {
+		wg := sync.WaitGroup{}
+		wg.Add(len(m.shards))
+		for _, shard := range m.shards {
+			go func(shard *ConcurrentMapShared[K, V]) {
+				shard.RLock()
+				for key := range shard.items {
+					ch <- key
+				}
+				shard.RUnlock()
+				wg.Done()
+			}(shard)
+		}
+		wg.Wait()
+		close(ch)
+	}()
+
+	keys := make([]K, 0, count)
+	for k := range ch {
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+// MarshalJSON reveals the map as a plain JSON object.
+func (m ConcurrentMap[K, V]) MarshalJSON() ([]byte, error) {
+	return json.Marshal(m.Items())
+}
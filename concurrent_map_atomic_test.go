@@ -0,0 +1,81 @@
+package cmap
+
+import "testing"
+
+func TestConcurrentMapLoadOrStore(t *testing.T) {
+	m := NewTyped[int]()
+
+	actual, loaded := m.LoadOrStore(uint16(1), 1)
+	if loaded || actual != 1 {
+		t.Error("expected first LoadOrStore to store and return the new value")
+	}
+
+	actual, loaded = m.LoadOrStore(uint16(1), 2)
+	if !loaded || actual != 1 {
+		t.Error("expected second LoadOrStore to leave the value untouched")
+	}
+}
+
+func TestConcurrentMapSwap(t *testing.T) {
+	m := NewTyped[int]()
+
+	prev, loaded := m.Swap(uint16(1), 1)
+	if loaded || prev != 0 {
+		t.Error("expected Swap on a missing key to report not loaded")
+	}
+
+	prev, loaded = m.Swap(uint16(1), 2)
+	if !loaded || prev != 1 {
+		t.Error("expected Swap to return the previous value")
+	}
+
+	if v, _ := m.Get(uint16(1)); v != 2 {
+		t.Error("expected Swap to store the new value")
+	}
+}
+
+func TestConcurrentMapCompareAndSwap(t *testing.T) {
+	m := NewTyped[int]()
+	m.Set(uint16(1), 1)
+
+	if m.CompareAndSwap(uint16(1), 99, 2) {
+		t.Error("expected CompareAndSwap to fail against the wrong old value")
+	}
+	if !m.CompareAndSwap(uint16(1), 1, 2) {
+		t.Error("expected CompareAndSwap to succeed against the right old value")
+	}
+	if v, _ := m.Get(uint16(1)); v != 2 {
+		t.Error("expected CompareAndSwap to store the new value")
+	}
+	if m.CompareAndSwap(uint16(2), 0, 1) {
+		t.Error("expected CompareAndSwap against a missing key to fail")
+	}
+}
+
+func TestConcurrentMapCompareAndSwapWithEqual(t *testing.T) {
+	type box struct{ n int }
+	m := NewTyped[box]().WithEqual(func(a, b box) bool { return a.n == b.n })
+	m.Set(uint16(1), box{n: 1})
+
+	if !m.CompareAndSwap(uint16(1), box{n: 1}, box{n: 2}) {
+		t.Error("expected CompareAndSwap to succeed using the custom Equal")
+	}
+	if v, _ := m.Get(uint16(1)); v.n != 2 {
+		t.Error("expected CompareAndSwap to store the new value")
+	}
+}
+
+func TestConcurrentMapCompareAndDelete(t *testing.T) {
+	m := NewTyped[int]()
+	m.Set(uint16(1), 1)
+
+	if m.CompareAndDelete(uint16(1), 99) {
+		t.Error("expected CompareAndDelete to fail against the wrong old value")
+	}
+	if !m.CompareAndDelete(uint16(1), 1) {
+		t.Error("expected CompareAndDelete to succeed against the right old value")
+	}
+	if _, ok := m.Get(uint16(1)); ok {
+		t.Error("expected the key to be gone after CompareAndDelete")
+	}
+}
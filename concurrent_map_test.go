@@ -13,7 +13,7 @@ type Animal struct {
 
 func TestMapCreation(t *testing.T) {
 	m := New()
-	if m == nil {
+	if m.shards == nil {
 		t.Error("map is null.")
 	}
 
@@ -390,55 +390,53 @@ func TestFnv32(t *testing.T) {
 	}
 }
 
-// func TestUpsert(t *testing.T) {
-// 	dolphin := Animal{uint16(11)}
-// 	whale := Animal{12}
-// 	tiger := Animal{13}
-// 	lion := Animal{14}
-
-// 	cb := func(exists bool, valueInMap interface{}, newValue interface{}) interface{} {
-// 		nv := newValue.(Animal)
-// 		if !exists {
-// 			return []Animal{nv}
-// 		}
-// 		res := valueInMap.([]Animal)
-// 		return append(res, nv)
-// 	}
-
-// 	m := New()
-// 	m.Set("marine", []Animal{dolphin})
-// 	m.Upsert("marine", whale, cb)
-// 	m.Upsert("predator", tiger, cb)
-// 	m.Upsert("predator", lion, cb)
-
-// 	if m.Count() != 2 {
-// 		t.Error("map should contain exactly two elements.")
-// 	}
-
-// 	compare := func(a, b []Animal) bool {
-// 		if a == nil || b == nil {
-// 			return false
-// 		}
-
-// 		if len(a) != len(b) {
-// 			return false
-// 		}
-
-// 		for i, v := range a {
-// 			if v != b[i] {
-// 				return false
-// 			}
-// 		}
-// 		return true
-// 	}
-
-// 	marineAnimals, ok := m.Get("marine")
-// 	if !ok || !compare(marineAnimals.([]Animal), []Animal{dolphin, whale}) {
-// 		t.Error("Set, then Upsert failed")
-// 	}
-
-// 	predators, ok := m.Get("predator")
-// 	if !ok || !compare(predators.([]Animal), []Animal{tiger, lion}) {
-// 		t.Error("Upsert, then Upsert failed")
-// 	}
-// }
+func TestUpsert(t *testing.T) {
+	dolphin := Animal{uint16(11)}
+	whale := Animal{12}
+	tiger := Animal{13}
+	lion := Animal{14}
+
+	cb := func(exists bool, valueInMap []Animal, newValue []Animal) []Animal {
+		if !exists {
+			return newValue
+		}
+		return append(valueInMap, newValue...)
+	}
+
+	m := NewWithCustomShardingFunction[string, []Animal](ShardString)
+	m.Set("marine", []Animal{dolphin})
+	m.Upsert("marine", []Animal{whale}, cb)
+	m.Upsert("predator", []Animal{tiger}, cb)
+	m.Upsert("predator", []Animal{lion}, cb)
+
+	if m.Count() != 2 {
+		t.Error("map should contain exactly two elements.")
+	}
+
+	compare := func(a, b []Animal) bool {
+		if a == nil || b == nil {
+			return false
+		}
+
+		if len(a) != len(b) {
+			return false
+		}
+
+		for i, v := range a {
+			if v != b[i] {
+				return false
+			}
+		}
+		return true
+	}
+
+	marineAnimals, ok := m.Get("marine")
+	if !ok || !compare(marineAnimals, []Animal{dolphin, whale}) {
+		t.Error("Set, then Upsert failed")
+	}
+
+	predators, ok := m.Get("predator")
+	if !ok || !compare(predators, []Animal{tiger, lion}) {
+		t.Error("Upsert, then Upsert failed")
+	}
+}
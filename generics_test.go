@@ -0,0 +1,67 @@
+package cmap
+
+import "testing"
+
+// TestGetTyped exercises a ConcurrentMap instantiated with a concrete value
+// type, showing that callers no longer need a type assertion to use the
+// result of Get (contrast with TestGet, which still uses interface{}).
+func TestGetTyped(t *testing.T) {
+	m := NewTyped[Animal]()
+
+	elephant := Animal{uint16(1)}
+	m.Set(uint16(1), elephant)
+
+	got, ok := m.Get(uint16(1))
+	if !ok {
+		t.Error("ok should be true for item stored within the map.")
+	}
+	if got.name != uint16(1) {
+		t.Error("item was modified.")
+	}
+}
+
+func TestNewUntypedCompat(t *testing.T) {
+	m := New()
+	m.Set(uint16(1), Animal{uint16(1)})
+
+	v, ok := m.Get(uint16(1))
+	if !ok {
+		t.Error("ok should be true for item stored within the map.")
+	}
+	if v.(Animal).name != uint16(1) {
+		t.Error("item was modified.")
+	}
+}
+
+func TestNewWithCustomShardingFunction(t *testing.T) {
+	m := NewWithCustomShardingFunction[string, int](ShardString)
+
+	m.Set("a", 1)
+	m.Set("b", 2)
+
+	if m.Count() != 2 {
+		t.Error("map should contain exactly two elements.")
+	}
+
+	v, ok := m.Get("a")
+	if !ok || v != 1 {
+		t.Error("expected to find key \"a\" with value 1.")
+	}
+}
+
+func TestShardIntegerAndBytes(t *testing.T) {
+	m := NewWithCustomShardingFunction[int64, string](ShardInteger[int64])
+	m.Set(42, "answer")
+
+	if v, ok := m.Get(42); !ok || v != "answer" {
+		t.Error("expected to find key 42 with value \"answer\".")
+	}
+
+	if ShardBytesFNV32([]byte("ABC")) != fnv32("ABC") {
+		t.Error("ShardBytesFNV32 should agree with fnv32 on the same bytes.")
+	}
+
+	if ShardBytesXXHash([]byte("ABC")) == 0 {
+		t.Error("ShardBytesXXHash should produce a non-zero hash for a non-empty key.")
+	}
+}
@@ -0,0 +1,358 @@
+package cmap
+
+import (
+	"reflect"
+	"sync/atomic"
+)
+
+// Hasher produces a 64-bit hash for a key, used by HashTrieMap to choose a
+// path through the trie. Unlike Sharder it must spread bits across the
+// full width: every 4-bit chunk of the result is used to pick a branch.
+type Hasher[K comparable] func(key K) uint64
+
+const (
+	trieBitsPerLevel = 4
+	trieFanout       = 1 << trieBitsPerLevel
+	trieMaxLevel     = 64 / trieBitsPerLevel
+)
+
+func trieChunk(hash uint64, level int) int {
+	return int((hash >> uint(level*trieBitsPerLevel)) & (trieFanout - 1))
+}
+
+// hashTrieNode is an internal trie node: a fixed fan-out array of slots,
+// each either empty, holding a child node, or holding a leaf.
+type hashTrieNode[K comparable, V any] struct {
+	slots [trieFanout]atomic.Pointer[hashTrieSlot[K, V]]
+}
+
+// hashTrieSlot is the immutable value a slot's pointer is swung between.
+// Exactly one of node or leaf is non-nil.
+type hashTrieSlot[K comparable, V any] struct {
+	node *hashTrieNode[K, V]
+	leaf *hashTrieLeaf[K, V]
+}
+
+// hashTrieLeaf is an immutable key/value record. Below trieMaxLevel a leaf
+// slot always holds exactly one; at trieMaxLevel, once every hash bit has
+// been consumed, colliding keys are chained off next instead of splitting
+// the tree further.
+type hashTrieLeaf[K comparable, V any] struct {
+	key  K
+	val  V
+	next *hashTrieLeaf[K, V]
+}
+
+// HashTrieMap is a lock-free map keyed by K: reads follow atomic pointers
+// over immutable nodes and never block, while writers race via CAS and
+// retry from the root on conflict. It trades the shard locks of
+// ConcurrentMap for better scaling under heavy concurrent read/write
+// access across a large key space.
+type HashTrieMap[K comparable, V any] struct {
+	root   *hashTrieNode[K, V]
+	hasher Hasher[K]
+	equal  Equal[V]
+}
+
+// NewHashTrieMap creates a HashTrieMap, hashing keys via hasher.
+func NewHashTrieMap[K comparable, V any](hasher Hasher[K]) *HashTrieMap[K, V] {
+	return &HashTrieMap[K, V]{root: &hashTrieNode[K, V]{}, hasher: hasher}
+}
+
+// WithEqual sets m to compare values with equal instead of the
+// reflect.DeepEqual fallback, for use by CompareAndSwap and
+// CompareAndDelete, and returns m for chaining.
+func (m *HashTrieMap[K, V]) WithEqual(equal Equal[V]) *HashTrieMap[K, V] {
+	m.equal = equal
+	return m
+}
+
+func (m *HashTrieMap[K, V]) equalFunc() Equal[V] {
+	if m.equal != nil {
+		return m.equal
+	}
+	return func(a, b V) bool { return reflect.DeepEqual(a, b) }
+}
+
+func leafFind[K comparable, V any](l *hashTrieLeaf[K, V], key K) (*hashTrieLeaf[K, V], bool) {
+	for ; l != nil; l = l.next {
+		if l.key == key {
+			return l, true
+		}
+	}
+	return nil, false
+}
+
+// leafUpsert returns a new chain with key set to val, reusing the
+// untouched tail of the original chain.
+func leafUpsert[K comparable, V any](l *hashTrieLeaf[K, V], key K, val V) *hashTrieLeaf[K, V] {
+	if l == nil {
+		return &hashTrieLeaf[K, V]{key: key, val: val}
+	}
+	if l.key == key {
+		return &hashTrieLeaf[K, V]{key: key, val: val, next: l.next}
+	}
+	return &hashTrieLeaf[K, V]{key: l.key, val: l.val, next: leafUpsert(l.next, key, val)}
+}
+
+// leafWithout returns a new chain with key removed, or nil if that was the
+// only entry.
+func leafWithout[K comparable, V any](l *hashTrieLeaf[K, V], key K) *hashTrieLeaf[K, V] {
+	if l == nil {
+		return nil
+	}
+	if l.key == key {
+		return l.next
+	}
+	return &hashTrieLeaf[K, V]{key: l.key, val: l.val, next: leafWithout(l.next, key)}
+}
+
+// Load returns the value stored for key, if any.
+func (m *HashTrieMap[K, V]) Load(key K) (V, bool) {
+	hash := m.hasher(key)
+	node := m.root
+	for level := 0; ; level++ {
+		slot := node.slots[trieChunk(hash, level)].Load()
+		if slot == nil {
+			var zero V
+			return zero, false
+		}
+		if slot.node != nil {
+			node = slot.node
+			continue
+		}
+		if leaf, ok := leafFind(slot.leaf, key); ok {
+			return leaf.val, true
+		}
+		var zero V
+		return zero, false
+	}
+}
+
+// buildSplit builds a subtree holding both a (hash, single-entry chain)
+// pair already in the trie and a new entry that collided with it at
+// level, descending further levels until their paths diverge or the hash
+// is fully consumed.
+func buildSplit[K comparable, V any](level int, existingHash uint64, existing *hashTrieLeaf[K, V], newHash uint64, newEntry *hashTrieLeaf[K, V]) *hashTrieNode[K, V] {
+	node := &hashTrieNode[K, V]{}
+	eIdx := trieChunk(existingHash, level)
+	nIdx := trieChunk(newHash, level)
+	switch {
+	case level == trieMaxLevel:
+		// Hash fully consumed and still colliding: chain rather than
+		// recurse forever.
+		node.slots[eIdx].Store(&hashTrieSlot[K, V]{leaf: &hashTrieLeaf[K, V]{key: newEntry.key, val: newEntry.val, next: existing}})
+	case eIdx != nIdx:
+		node.slots[eIdx].Store(&hashTrieSlot[K, V]{leaf: existing})
+		node.slots[nIdx].Store(&hashTrieSlot[K, V]{leaf: newEntry})
+	default:
+		node.slots[eIdx].Store(&hashTrieSlot[K, V]{node: buildSplit(level+1, existingHash, existing, newHash, newEntry)})
+	}
+	return node
+}
+
+// upsertResult is returned by casUpsert, the CAS loop shared by the
+// mutating methods below.
+type upsertResult[V any] struct {
+	val     V
+	found   bool
+	applied bool
+}
+
+// casUpsert walks the trie for key, applying decide to whatever is
+// currently there, and CAS's the result in. decide is called with the
+// existing value (if any) and returns the value to store, whether an
+// existing entry should be deleted instead, and whether to apply any
+// change at all. It may be invoked more than once if a concurrent writer
+// wins the same slot first.
+func (m *HashTrieMap[K, V]) casUpsert(key K, decide func(old V, found bool) (newVal V, del bool, apply bool)) upsertResult[V] {
+	hash := m.hasher(key)
+	for {
+		node := m.root
+		for level := 0; ; level++ {
+			idx := trieChunk(hash, level)
+			slotPtr := &node.slots[idx]
+			old := slotPtr.Load()
+
+			switch {
+			case old == nil:
+				var zero V
+				newVal, del, apply := decide(zero, false)
+				if !apply || del {
+					return upsertResult[V]{found: false, applied: false}
+				}
+				newSlot := &hashTrieSlot[K, V]{leaf: &hashTrieLeaf[K, V]{key: key, val: newVal}}
+				if slotPtr.CompareAndSwap(nil, newSlot) {
+					return upsertResult[V]{found: false, applied: true}
+				}
+				// Lost the race for this slot; restart from the root.
+
+			case old.node != nil:
+				node = old.node
+				continue
+
+			default:
+				leaf, found := leafFind(old.leaf, key)
+				if found {
+					newVal, del, apply := decide(leaf.val, true)
+					if !apply {
+						return upsertResult[V]{val: leaf.val, found: true, applied: false}
+					}
+					var newChain *hashTrieLeaf[K, V]
+					if del {
+						newChain = leafWithout(old.leaf, key)
+					} else {
+						newChain = leafUpsert(old.leaf, key, newVal)
+					}
+					var newSlot *hashTrieSlot[K, V]
+					if newChain != nil {
+						newSlot = &hashTrieSlot[K, V]{leaf: newChain}
+					}
+					if slotPtr.CompareAndSwap(old, newSlot) {
+						return upsertResult[V]{val: leaf.val, found: true, applied: true}
+					}
+					// Raced; restart.
+				} else {
+					newVal, del, apply := decide(*new(V), false)
+					if !apply || del {
+						return upsertResult[V]{found: false, applied: false}
+					}
+					newEntry := &hashTrieLeaf[K, V]{key: key, val: newVal}
+					if level == trieMaxLevel {
+						newSlot := &hashTrieSlot[K, V]{leaf: &hashTrieLeaf[K, V]{key: key, val: newVal, next: old.leaf}}
+						if slotPtr.CompareAndSwap(old, newSlot) {
+							return upsertResult[V]{found: false, applied: true}
+						}
+					} else {
+						existingHash := m.hasher(old.leaf.key)
+						split := buildSplit(level+1, existingHash, old.leaf, hash, newEntry)
+						newSlot := &hashTrieSlot[K, V]{node: split}
+						if slotPtr.CompareAndSwap(old, newSlot) {
+							return upsertResult[V]{found: false, applied: true}
+						}
+					}
+					// Raced; restart.
+				}
+			}
+			break
+		}
+	}
+}
+
+// Store sets the value for key, overwriting any existing entry.
+func (m *HashTrieMap[K, V]) Store(key K, val V) {
+	m.casUpsert(key, func(V, bool) (V, bool, bool) { return val, false, true })
+}
+
+// LoadOrStore returns the existing value for key if present; otherwise it
+// stores and returns val.
+func (m *HashTrieMap[K, V]) LoadOrStore(key K, val V) (actual V, loaded bool) {
+	res := m.casUpsert(key, func(old V, found bool) (V, bool, bool) {
+		if found {
+			return old, false, false
+		}
+		return val, false, true
+	})
+	if res.found {
+		return res.val, true
+	}
+	return val, false
+}
+
+// Swap stores val for key, returning the previous value if any.
+func (m *HashTrieMap[K, V]) Swap(key K, val V) (previous V, loaded bool) {
+	res := m.casUpsert(key, func(V, bool) (V, bool, bool) { return val, false, true })
+	return res.val, res.found
+}
+
+// LoadAndDelete deletes the value for key, returning the previous value if
+// any.
+func (m *HashTrieMap[K, V]) LoadAndDelete(key K) (value V, loaded bool) {
+	res := m.casUpsert(key, func(old V, found bool) (V, bool, bool) {
+		return old, true, found
+	})
+	return res.val, res.found
+}
+
+// CompareAndSwap stores new for key only if its current value is old, as
+// judged by equalFunc (see WithEqual).
+func (m *HashTrieMap[K, V]) CompareAndSwap(key K, old, new V) (swapped bool) {
+	equal := m.equalFunc()
+	res := m.casUpsert(key, func(cur V, found bool) (V, bool, bool) {
+		if !found || !equal(cur, old) {
+			return cur, false, false
+		}
+		return new, false, true
+	})
+	return res.applied
+}
+
+// CompareAndDelete deletes the entry for key only if its current value is
+// old, as judged by equalFunc (see WithEqual).
+func (m *HashTrieMap[K, V]) CompareAndDelete(key K, old V) (deleted bool) {
+	equal := m.equalFunc()
+	res := m.casUpsert(key, func(cur V, found bool) (V, bool, bool) {
+		if !found || !equal(cur, old) {
+			return cur, false, false
+		}
+		return cur, true, true
+	})
+	return res.applied
+}
+
+// All returns a channel carrying every key/value pair currently in the
+// map. Like ConcurrentMap.Iter, reading it walks live nodes rather than a
+// snapshot, so entries stored or deleted during the walk may or may not be
+// observed.
+func (m *HashTrieMap[K, V]) All() <-chan Tuple[K, V] {
+	ch := make(chan Tuple[K, V])
+	go func() {
+		m.walk(m.root, ch)
+		close(ch)
+	}()
+	return ch
+}
+
+func (m *HashTrieMap[K, V]) walk(node *hashTrieNode[K, V], ch chan<- Tuple[K, V]) {
+	for i := range node.slots {
+		slot := node.slots[i].Load()
+		if slot == nil {
+			continue
+		}
+		if slot.node != nil {
+			m.walk(slot.node, ch)
+			continue
+		}
+		for l := slot.leaf; l != nil; l = l.next {
+			ch <- Tuple[K, V]{Key: l.key, Val: l.val}
+		}
+	}
+}
+
+// Range calls f for every key/value pair currently in the map, stopping
+// early if f returns false. As with All, it observes live nodes rather
+// than a consistent snapshot.
+func (m *HashTrieMap[K, V]) Range(f func(key K, value V) bool) {
+	rangeNode(m.root, f)
+}
+
+func rangeNode[K comparable, V any](node *hashTrieNode[K, V], f func(key K, value V) bool) bool {
+	for i := range node.slots {
+		slot := node.slots[i].Load()
+		if slot == nil {
+			continue
+		}
+		if slot.node != nil {
+			if !rangeNode(slot.node, f) {
+				return false
+			}
+			continue
+		}
+		for l := slot.leaf; l != nil; l = l.next {
+			if !f(l.key, l.val) {
+				return false
+			}
+		}
+	}
+	return true
+}
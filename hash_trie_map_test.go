@@ -0,0 +1,191 @@
+package cmap
+
+import (
+	"math/rand"
+	"sync"
+	"testing"
+)
+
+func TestHashTrieMapLoadStore(t *testing.T) {
+	m := NewHashTrieMap[string, int](HashString)
+
+	if _, ok := m.Load("a"); ok {
+		t.Error("expected missing key to report not found")
+	}
+
+	m.Store("a", 1)
+	if v, ok := m.Load("a"); !ok || v != 1 {
+		t.Error("expected to load the value just stored")
+	}
+
+	m.Store("a", 2)
+	if v, ok := m.Load("a"); !ok || v != 2 {
+		t.Error("expected Store to overwrite the existing value")
+	}
+}
+
+func TestHashTrieMapLoadOrStore(t *testing.T) {
+	m := NewHashTrieMap[string, int](HashString)
+
+	actual, loaded := m.LoadOrStore("a", 1)
+	if loaded || actual != 1 {
+		t.Error("expected first LoadOrStore to store and return the new value")
+	}
+
+	actual, loaded = m.LoadOrStore("a", 2)
+	if !loaded || actual != 1 {
+		t.Error("expected second LoadOrStore to leave the value untouched")
+	}
+}
+
+func TestHashTrieMapSwap(t *testing.T) {
+	m := NewHashTrieMap[string, int](HashString)
+
+	prev, loaded := m.Swap("a", 1)
+	if loaded || prev != 0 {
+		t.Error("expected Swap on a missing key to report not loaded")
+	}
+
+	prev, loaded = m.Swap("a", 2)
+	if !loaded || prev != 1 {
+		t.Error("expected Swap to return the previous value")
+	}
+
+	if v, _ := m.Load("a"); v != 2 {
+		t.Error("expected Swap to store the new value")
+	}
+}
+
+func TestHashTrieMapCompareAndSwap(t *testing.T) {
+	m := NewHashTrieMap[string, int](HashString)
+	m.Store("a", 1)
+
+	if m.CompareAndSwap("a", 99, 2) {
+		t.Error("expected CompareAndSwap to fail against the wrong old value")
+	}
+	if !m.CompareAndSwap("a", 1, 2) {
+		t.Error("expected CompareAndSwap to succeed against the right old value")
+	}
+	if v, _ := m.Load("a"); v != 2 {
+		t.Error("expected CompareAndSwap to store the new value")
+	}
+	if m.CompareAndSwap("missing", 0, 1) {
+		t.Error("expected CompareAndSwap against a missing key to fail")
+	}
+}
+
+func TestHashTrieMapCompareAndDelete(t *testing.T) {
+	m := NewHashTrieMap[string, int](HashString)
+	m.Store("a", 1)
+
+	if m.CompareAndDelete("a", 99) {
+		t.Error("expected CompareAndDelete to fail against the wrong old value")
+	}
+	if !m.CompareAndDelete("a", 1) {
+		t.Error("expected CompareAndDelete to succeed against the right old value")
+	}
+	if _, ok := m.Load("a"); ok {
+		t.Error("expected the key to be gone after CompareAndDelete")
+	}
+}
+
+func TestHashTrieMapCompareAndSwapWithEqual(t *testing.T) {
+	m := NewHashTrieMap[string, []byte](HashString).WithEqual(func(a, b []byte) bool {
+		return string(a) == string(b)
+	})
+	m.Store("a", []byte("1"))
+
+	if m.CompareAndSwap("a", []byte("99"), []byte("2")) {
+		t.Error("expected CompareAndSwap to fail against the wrong old value")
+	}
+	if !m.CompareAndSwap("a", []byte("1"), []byte("2")) {
+		t.Error("expected CompareAndSwap to succeed against the right old value")
+	}
+	if v, _ := m.Load("a"); string(v) != "2" {
+		t.Error("expected CompareAndSwap to store the new value")
+	}
+}
+
+func TestHashTrieMapLoadAndDelete(t *testing.T) {
+	m := NewHashTrieMap[string, int](HashString)
+	m.Store("a", 1)
+
+	v, loaded := m.LoadAndDelete("a")
+	if !loaded || v != 1 {
+		t.Error("expected LoadAndDelete to return the removed value")
+	}
+	if _, ok := m.Load("a"); ok {
+		t.Error("expected the key to be gone after LoadAndDelete")
+	}
+
+	if _, loaded := m.LoadAndDelete("a"); loaded {
+		t.Error("expected LoadAndDelete on a missing key to report not loaded")
+	}
+}
+
+func TestHashTrieMapAllAndRange(t *testing.T) {
+	m := NewHashTrieMap[int, int](HashInteger[int])
+	for i := 0; i < 200; i++ {
+		m.Store(i, i*i)
+	}
+
+	seen := make(map[int]int)
+	for tup := range m.All() {
+		seen[tup.Key] = tup.Val
+	}
+	if len(seen) != 200 {
+		t.Errorf("expected 200 entries from All, got %d", len(seen))
+	}
+
+	count := 0
+	m.Range(func(key, value int) bool {
+		if value != key*key {
+			t.Errorf("unexpected value %d for key %d", value, key)
+		}
+		count++
+		return true
+	})
+	if count != 200 {
+		t.Errorf("expected Range to visit 200 entries, got %d", count)
+	}
+
+	stopped := 0
+	m.Range(func(key, value int) bool {
+		stopped++
+		return false
+	})
+	if stopped != 1 {
+		t.Errorf("expected Range to stop after the first entry, got %d", stopped)
+	}
+}
+
+func TestHashTrieMapConcurrentLoadOrStoreAndCompareAndDelete(t *testing.T) {
+	m := NewHashTrieMap[int, int](HashInteger[int])
+	const keys = 64
+	const iterations = 2000
+
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func(seed int64) {
+			defer wg.Done()
+			r := rand.New(rand.NewSource(seed))
+			for i := 0; i < iterations; i++ {
+				key := r.Intn(keys)
+				if actual, loaded := m.LoadOrStore(key, key); loaded && actual != key {
+					t.Errorf("LoadOrStore returned wrong actual value for key %d: %d", key, actual)
+				}
+				m.CompareAndDelete(key, key)
+			}
+		}(int64(g))
+	}
+	wg.Wait()
+
+	// Every surviving entry must still satisfy value == key.
+	m.Range(func(key, value int) bool {
+		if value != key {
+			t.Errorf("corrupted entry: key %d has value %d", key, value)
+		}
+		return true
+	})
+}
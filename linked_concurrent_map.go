@@ -0,0 +1,380 @@
+package cmap
+
+import (
+	"bytes"
+	"container/heap"
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"sync"
+	"sync/atomic"
+)
+
+// linkedEntry is one record in a shard's doubly-linked list, stamped with
+// the map-wide sequence number in effect when it was last set.
+type linkedEntry[K comparable, V any] struct {
+	key        K
+	val        V
+	seq        uint64
+	prev, next *linkedEntry[K, V]
+}
+
+// linkedShard is a single shard of a LinkedConcurrentMap: a map for O(1)
+// lookup plus a doubly-linked list (oldest at head, newest at tail) for
+// order-preserving iteration.
+type linkedShard[K comparable, V any] struct {
+	items      map[K]*linkedEntry[K, V]
+	head, tail *linkedEntry[K, V]
+	sync.RWMutex
+}
+
+func (s *linkedShard[K, V]) pushBack(e *linkedEntry[K, V]) {
+	e.prev, e.next = s.tail, nil
+	if s.tail != nil {
+		s.tail.next = e
+	} else {
+		s.head = e
+	}
+	s.tail = e
+}
+
+func (s *linkedShard[K, V]) unlink(e *linkedEntry[K, V]) {
+	if e.prev != nil {
+		e.prev.next = e.next
+	} else {
+		s.head = e.next
+	}
+	if e.next != nil {
+		e.next.prev = e.prev
+	} else {
+		s.tail = e.prev
+	}
+	e.prev, e.next = nil, nil
+}
+
+// LinkedConcurrentMap is a ConcurrentMap that additionally remembers
+// insertion order: Set appends a new key to the back of its shard's list
+// (or moves an existing one there), and every ordered iteration method
+// (Iter, IterBuffered, IterCb, Keys, MarshalJSON) merges the shards'
+// lists by the sequence number stamped on each entry, so keys always come
+// out in true insertion order regardless of which shard holds them. Items
+// returns a plain Go map, so its order is not preserved.
+type LinkedConcurrentMap[K comparable, V any] struct {
+	shards   []*linkedShard[K, V]
+	sharding Sharder[K]
+	seq      *atomic.Uint64
+}
+
+func createLinked[K comparable, V any](sharding Sharder[K]) LinkedConcurrentMap[K, V] {
+	m := LinkedConcurrentMap[K, V]{
+		sharding: sharding,
+		shards:   make([]*linkedShard[K, V], SHARD_COUNT),
+		seq:      new(atomic.Uint64),
+	}
+	for i := 0; i < SHARD_COUNT; i++ {
+		m.shards[i] = &linkedShard[K, V]{items: make(map[K]*linkedEntry[K, V])}
+	}
+	return m
+}
+
+// NewLinked creates a LinkedConcurrentMap keyed by uint16, matching New.
+func NewLinked[V any]() LinkedConcurrentMap[uint16, V] {
+	return createLinked[uint16, V](ShardUint16)
+}
+
+// NewLinkedWithCustomShardingFunction creates a LinkedConcurrentMap over an
+// arbitrary comparable key type K, assigning keys to shards via sharding.
+func NewLinkedWithCustomShardingFunction[K comparable, V any](sharding Sharder[K]) LinkedConcurrentMap[K, V] {
+	return createLinked[K, V](sharding)
+}
+
+// GetShard returns the shard responsible for key.
+func (m LinkedConcurrentMap[K, V]) GetShard(key K) *linkedShard[K, V] {
+	return m.shards[uint(m.sharding(key))%uint(len(m.shards))]
+}
+
+// Set sets the given value under the given key. A key being set for the
+// first time is appended to its shard's list; a key that already exists is
+// moved to the back, as though it had just been inserted.
+func (m LinkedConcurrentMap[K, V]) Set(key K, value V) {
+	shard := m.GetShard(key)
+	shard.Lock()
+	if e, ok := shard.items[key]; ok {
+		e.val = value
+		e.seq = m.seq.Add(1)
+		shard.unlink(e)
+		shard.pushBack(e)
+	} else {
+		e := &linkedEntry[K, V]{key: key, val: value, seq: m.seq.Add(1)}
+		shard.items[key] = e
+		shard.pushBack(e)
+	}
+	shard.Unlock()
+}
+
+// SetIfAbsent sets the given value under the given key if no value was
+// already associated with it.
+func (m LinkedConcurrentMap[K, V]) SetIfAbsent(key K, value V) bool {
+	shard := m.GetShard(key)
+	shard.Lock()
+	_, ok := shard.items[key]
+	if !ok {
+		e := &linkedEntry[K, V]{key: key, val: value, seq: m.seq.Add(1)}
+		shard.items[key] = e
+		shard.pushBack(e)
+	}
+	shard.Unlock()
+	return !ok
+}
+
+// Get retrieves the value associated with key.
+func (m LinkedConcurrentMap[K, V]) Get(key K) (V, bool) {
+	shard := m.GetShard(key)
+	shard.RLock()
+	e, ok := shard.items[key]
+	shard.RUnlock()
+	if !ok {
+		var zero V
+		return zero, false
+	}
+	return e.val, true
+}
+
+// Count returns the number of elements within the map.
+func (m LinkedConcurrentMap[K, V]) Count() int {
+	count := 0
+	for _, shard := range m.shards {
+		shard.RLock()
+		count += len(shard.items)
+		shard.RUnlock()
+	}
+	return count
+}
+
+// Has looks up an item under the given key, reporting whether it's present.
+func (m LinkedConcurrentMap[K, V]) Has(key K) bool {
+	shard := m.GetShard(key)
+	shard.RLock()
+	_, ok := shard.items[key]
+	shard.RUnlock()
+	return ok
+}
+
+// Remove deletes the given key from the map.
+func (m LinkedConcurrentMap[K, V]) Remove(key K) {
+	shard := m.GetShard(key)
+	shard.Lock()
+	if e, ok := shard.items[key]; ok {
+		shard.unlink(e)
+		delete(shard.items, key)
+	}
+	shard.Unlock()
+}
+
+// Pop removes an element from the map and returns it.
+func (m LinkedConcurrentMap[K, V]) Pop(key K) (v V, exists bool) {
+	shard := m.GetShard(key)
+	shard.Lock()
+	e, ok := shard.items[key]
+	if ok {
+		v = e.val
+		shard.unlink(e)
+		delete(shard.items, key)
+	}
+	shard.Unlock()
+	return v, ok
+}
+
+// IsEmpty checks if the map is empty.
+func (m LinkedConcurrentMap[K, V]) IsEmpty() bool {
+	return m.Count() == 0
+}
+
+// seqSnapshotShard copies a shard's list, oldest to newest, pairing each
+// entry with the seq it was stamped with, without holding the shard's lock
+// any longer than the copy itself takes.
+func (m LinkedConcurrentMap[K, V]) seqSnapshotShard(shard *linkedShard[K, V]) []linkedSeqTuple[K, V] {
+	shard.RLock()
+	defer shard.RUnlock()
+	tuples := make([]linkedSeqTuple[K, V], 0, len(shard.items))
+	for e := shard.head; e != nil; e = e.next {
+		tuples = append(tuples, linkedSeqTuple[K, V]{Tuple: Tuple[K, V]{Key: e.key, Val: e.val}, seq: e.seq})
+	}
+	return tuples
+}
+
+// linkedSeqTuple pairs a key/value pair with the sequence number it was
+// stamped with, so ordered iteration can merge several already
+// seq-ordered shard lists.
+type linkedSeqTuple[K comparable, V any] struct {
+	Tuple[K, V]
+	seq uint64
+}
+
+// ordered merges every shard's list (each already ascending by seq, since
+// Set only ever appends with a fresher seq than anything before it) into a
+// single slice in true insertion order, via a k-way heap merge.
+func (m LinkedConcurrentMap[K, V]) ordered() []Tuple[K, V] {
+	lists := make([][]linkedSeqTuple[K, V], len(m.shards))
+	total := 0
+	for i, shard := range m.shards {
+		lists[i] = m.seqSnapshotShard(shard)
+		total += len(lists[i])
+	}
+
+	h := make(linkedMergeHeap[K, V], 0, len(lists))
+	for _, list := range lists {
+		if len(list) > 0 {
+			h = append(h, linkedMergeCursor[K, V]{list: list})
+		}
+	}
+	heap.Init(&h)
+
+	out := make([]Tuple[K, V], 0, total)
+	for h.Len() > 0 {
+		cur := h[0]
+		out = append(out, cur.list[0].Tuple)
+		if rest := cur.list[1:]; len(rest) > 0 {
+			h[0] = linkedMergeCursor[K, V]{list: rest}
+			heap.Fix(&h, 0)
+		} else {
+			heap.Pop(&h)
+		}
+	}
+	return out
+}
+
+// linkedMergeCursor is the head of one shard's remaining, still
+// seq-ordered, entries.
+type linkedMergeCursor[K comparable, V any] struct {
+	list []linkedSeqTuple[K, V]
+}
+
+// linkedMergeHeap is a min-heap of shard cursors, ordered by the seq at
+// the head of each cursor's list.
+type linkedMergeHeap[K comparable, V any] []linkedMergeCursor[K, V]
+
+func (h linkedMergeHeap[K, V]) Len() int { return len(h) }
+func (h linkedMergeHeap[K, V]) Less(i, j int) bool {
+	return h[i].list[0].seq < h[j].list[0].seq
+}
+func (h linkedMergeHeap[K, V]) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+func (h *linkedMergeHeap[K, V]) Push(x interface{}) {
+	*h = append(*h, x.(linkedMergeCursor[K, V]))
+}
+func (h *linkedMergeHeap[K, V]) Pop() interface{} {
+	old := *h
+	n := len(old)
+	item := old[n-1]
+	*h = old[:n-1]
+	return item
+}
+
+// Iter returns a channel of key/value pairs in insertion order.
+func (m LinkedConcurrentMap[K, V]) Iter() <-chan Tuple[K, V] {
+	tuples := m.ordered()
+	ch := make(chan Tuple[K, V])
+	go func() {
+		for _, t := range tuples {
+			ch <- t
+		}
+		close(ch)
+	}()
+	return ch
+}
+
+// IterBuffered returns a buffered channel of key/value pairs in insertion
+// order.
+func (m LinkedConcurrentMap[K, V]) IterBuffered() <-chan Tuple[K, V] {
+	tuples := m.ordered()
+	ch := make(chan Tuple[K, V], len(tuples))
+	for _, t := range tuples {
+		ch <- t
+	}
+	close(ch)
+	return ch
+}
+
+// IterCb iterates the map in insertion order, calling fn for each
+// key/value pair.
+func (m LinkedConcurrentMap[K, V]) IterCb(fn IterCb[K, V]) {
+	for _, t := range m.ordered() {
+		fn(t.Key, t.Val)
+	}
+}
+
+// Items returns all items as a plain map. Since a plain Go map doesn't
+// preserve order, use Iter, IterBuffered, or Keys to observe insertion
+// order.
+func (m LinkedConcurrentMap[K, V]) Items() map[K]V {
+	tmp := make(map[K]V)
+	for _, t := range m.ordered() {
+		tmp[t.Key] = t.Val
+	}
+	return tmp
+}
+
+// Keys returns all keys, oldest first.
+func (m LinkedConcurrentMap[K, V]) Keys() []K {
+	tuples := m.ordered()
+	keys := make([]K, len(tuples))
+	for i, t := range tuples {
+		keys[i] = t.Key
+	}
+	return keys
+}
+
+// MarshalJSON renders the map as a JSON object with its members in
+// insertion order. Unlike json.Marshal on a plain map, key order is
+// preserved.
+func (m LinkedConcurrentMap[K, V]) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, t := range m.ordered() {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		keyText, err := marshalJSONMapKey(t.Key)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(keyText)
+		buf.WriteByte(':')
+		valJSON, err := json.Marshal(t.Val)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(valJSON)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// marshalJSONMapKey renders key as a JSON string, following the same rules
+// encoding/json uses for map keys: a TextMarshaler is honoured, otherwise
+// strings and integers are converted directly.
+func marshalJSONMapKey(key interface{}) ([]byte, error) {
+	v := reflect.ValueOf(key)
+	if v.Kind() == reflect.String {
+		return json.Marshal(v.String())
+	}
+
+	if tm, ok := key.(encoding.TextMarshaler); ok {
+		text, err := tm.MarshalText()
+		if err != nil {
+			return nil, err
+		}
+		return json.Marshal(string(text))
+	}
+
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return json.Marshal(strconv.FormatInt(v.Int(), 10))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return json.Marshal(strconv.FormatUint(v.Uint(), 10))
+	default:
+		return nil, fmt.Errorf("cmap: unsupported map key type %T", key)
+	}
+}
@@ -0,0 +1,131 @@
+package cmap
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestLinkedMapInsertionOrder(t *testing.T) {
+	m := NewLinked[int]()
+
+	order := []uint16{5, 1, 9, 3, 7}
+	for _, k := range order {
+		m.Set(k, int(k))
+	}
+
+	keys := m.Keys()
+	if len(keys) != len(order) {
+		t.Fatalf("expected %d keys, got %d", len(order), len(keys))
+	}
+	for i, k := range keys {
+		if k != order[i] {
+			t.Errorf("key %d: expected %d, got %d", i, order[i], k)
+		}
+	}
+}
+
+func TestLinkedMapSetMovesToBack(t *testing.T) {
+	m := NewLinked[int]()
+
+	m.Set(uint16(1), 1)
+	m.Set(uint16(2), 2)
+	m.Set(uint16(3), 3)
+	// Re-setting an existing key should move it to the back.
+	m.Set(uint16(1), 11)
+
+	keys := m.Keys()
+	expected := []uint16{2, 3, 1}
+	if len(keys) != len(expected) {
+		t.Fatalf("expected %d keys, got %d", len(expected), len(keys))
+	}
+	for i, k := range keys {
+		if k != expected[i] {
+			t.Errorf("key %d: expected %d, got %d", i, expected[i], k)
+		}
+	}
+
+	v, ok := m.Get(uint16(1))
+	if !ok || v != 11 {
+		t.Error("expected the moved key to keep its updated value")
+	}
+}
+
+func TestLinkedMapRemovePop(t *testing.T) {
+	m := NewLinked[int]()
+	m.Set(uint16(1), 1)
+	m.Set(uint16(2), 2)
+	m.Set(uint16(3), 3)
+
+	m.Remove(uint16(2))
+	if m.Has(uint16(2)) {
+		t.Error("expected key 2 to be removed")
+	}
+
+	v, ok := m.Pop(uint16(3))
+	if !ok || v != 3 {
+		t.Error("expected Pop to return the removed value")
+	}
+
+	keys := m.Keys()
+	if len(keys) != 1 || keys[0] != uint16(1) {
+		t.Errorf("expected only key 1 to remain, got %v", keys)
+	}
+}
+
+func TestLinkedMapIterMatchesInsertionOrder(t *testing.T) {
+	m := NewLinked[int]()
+	order := []uint16{10, 20, 30, 40, 50}
+	for _, k := range order {
+		m.Set(k, int(k))
+	}
+
+	var got []uint16
+	for tup := range m.Iter() {
+		got = append(got, tup.Key)
+	}
+	for i, k := range got {
+		if k != order[i] {
+			t.Errorf("Iter position %d: expected %d, got %d", i, order[i], k)
+		}
+	}
+
+	got = got[:0]
+	for tup := range m.IterBuffered() {
+		got = append(got, tup.Key)
+	}
+	for i, k := range got {
+		if k != order[i] {
+			t.Errorf("IterBuffered position %d: expected %d, got %d", i, order[i], k)
+		}
+	}
+
+	got = got[:0]
+	m.IterCb(func(key uint16, v int) {
+		got = append(got, key)
+	})
+	for i, k := range got {
+		if k != order[i] {
+			t.Errorf("IterCb position %d: expected %d, got %d", i, order[i], k)
+		}
+	}
+}
+
+func TestLinkedMapJSONMarshalPreservesOrder(t *testing.T) {
+	SHARD_COUNT = 4
+	defer func() { SHARD_COUNT = 32 }()
+
+	m := NewLinked[int]()
+	m.Set(uint16(3), 30)
+	m.Set(uint16(1), 10)
+	m.Set(uint16(2), 20)
+
+	j, err := json.Marshal(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `{"3":30,"1":10,"2":20}`
+	if string(j) != expected {
+		t.Errorf("got %s, expected %s", j, expected)
+	}
+}
@@ -0,0 +1,74 @@
+package cmap
+
+import (
+	"strconv"
+
+	"github.com/cespare/xxhash/v2"
+)
+
+// Integer is satisfied by every built-in signed or unsigned integer type,
+// for use with ShardInteger.
+type Integer interface {
+	~int | ~int8 | ~int16 | ~int32 | ~int64 |
+		~uint | ~uint8 | ~uint16 | ~uint32 | ~uint64 | ~uintptr
+}
+
+// fnv32 hashes key with the 32-bit FNV-1 algorithm.
+func fnv32(key string) uint32 {
+	hash := uint32(2166136261)
+	const prime32 = uint32(16777619)
+	keyLength := len(key)
+	for i := 0; i < keyLength; i++ {
+		hash *= prime32
+		hash ^= uint32(key[i])
+	}
+	return hash
+}
+
+// ShardUint16 is the Sharder this package has always used for its default,
+// uint16-keyed map.
+func ShardUint16(key uint16) uint32 {
+	return fnv32(strconv.Itoa(int(key)))
+}
+
+// ShardString is a Sharder for string keys, hashing via fnv32.
+func ShardString(key string) uint32 {
+	return fnv32(key)
+}
+
+// ShardInteger is a Sharder for any built-in integer key type, hashing its
+// decimal representation via fnv32.
+func ShardInteger[K Integer](key K) uint32 {
+	return fnv32(strconv.FormatInt(int64(key), 10))
+}
+
+// ShardBytesFNV32 hashes a byte slice via fnv32. []byte isn't comparable, so
+// it can't be used as K directly; this is for building a Sharder over a
+// comparable key that wraps or is derived from bytes (e.g. a fixed-size
+// array, or a string key holding raw bytes).
+func ShardBytesFNV32(key []byte) uint32 {
+	return fnv32(string(key))
+}
+
+// ShardBytesXXHash hashes a byte slice via xxhash, faster than
+// ShardBytesFNV32 for longer keys. Same caveat as ShardBytesFNV32 applies.
+func ShardBytesXXHash(key []byte) uint32 {
+	return uint32(xxhash.Sum64(key))
+}
+
+// HashString is a Hasher for string keys, for use with HashTrieMap.
+func HashString(key string) uint64 {
+	return xxhash.Sum64String(key)
+}
+
+// HashInteger is a Hasher for any built-in integer key type, for use with
+// HashTrieMap.
+func HashInteger[K Integer](key K) uint64 {
+	return xxhash.Sum64String(strconv.FormatInt(int64(key), 10))
+}
+
+// HashUint16 is a Hasher for uint16 keys, mirroring ShardUint16 for
+// HashTrieMap.
+func HashUint16(key uint16) uint64 {
+	return HashInteger(key)
+}
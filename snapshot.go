@@ -0,0 +1,59 @@
+package cmap
+
+import "encoding/json"
+
+// Snapshot is a point-in-time, immutable copy of a ConcurrentMap's
+// contents. It's safe to read concurrently from many goroutines, and
+// doesn't block writers to the live map once it has been taken.
+type Snapshot[K comparable, V any] struct {
+	data map[K]V
+}
+
+// Snapshot captures a globally consistent view of m: every shard's lock is
+// briefly held at once, in a fixed order, while its contents are copied.
+// Writers across the whole map resume as soon as the copy finishes, well
+// before the caller gets to do anything with the result - unlike Iter,
+// which holds a shard's lock for as long as the caller keeps ranging over
+// it, or IterBuffered, which copies each shard eagerly but without any
+// cross-shard consistency guarantee.
+func (m ConcurrentMap[K, V]) Snapshot() Snapshot[K, V] {
+	for _, shard := range m.shards {
+		shard.RLock()
+	}
+	data := make(map[K]V)
+	for _, shard := range m.shards {
+		for k, v := range shard.items {
+			data[k] = v
+		}
+	}
+	for _, shard := range m.shards {
+		shard.RUnlock()
+	}
+	return Snapshot[K, V]{data: data}
+}
+
+// Len returns the number of elements captured in the snapshot.
+func (s Snapshot[K, V]) Len() int {
+	return len(s.data)
+}
+
+// Get retrieves the value associated with key as of the snapshot.
+func (s Snapshot[K, V]) Get(key K) (V, bool) {
+	v, ok := s.data[key]
+	return v, ok
+}
+
+// Range calls f for every key/value pair in the snapshot, stopping early
+// if f returns false.
+func (s Snapshot[K, V]) Range(f func(key K, value V) bool) {
+	for k, v := range s.data {
+		if !f(k, v) {
+			return
+		}
+	}
+}
+
+// MarshalJSON renders the snapshot as a JSON object.
+func (s Snapshot[K, V]) MarshalJSON() ([]byte, error) {
+	return json.Marshal(s.data)
+}
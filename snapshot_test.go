@@ -0,0 +1,126 @@
+package cmap
+
+import (
+	"encoding/json"
+	"sync"
+	"testing"
+)
+
+func TestSnapshotBasics(t *testing.T) {
+	m := NewTyped[int]()
+	for i := 0; i < 50; i++ {
+		m.Set(uint16(i), i)
+	}
+
+	snap := m.Snapshot()
+	if snap.Len() != 50 {
+		t.Errorf("expected 50 entries, got %d", snap.Len())
+	}
+
+	v, ok := snap.Get(uint16(10))
+	if !ok || v != 10 {
+		t.Error("expected to find key 10 with value 10")
+	}
+
+	if _, ok := snap.Get(uint16(999)); ok {
+		t.Error("expected a missing key to report not found")
+	}
+
+	count := 0
+	snap.Range(func(key uint16, value int) bool {
+		if int(key) != value {
+			t.Errorf("unexpected value %d for key %d", value, key)
+		}
+		count++
+		return true
+	})
+	if count != 50 {
+		t.Errorf("expected Range to visit 50 entries, got %d", count)
+	}
+}
+
+func TestSnapshotRangeStopsEarly(t *testing.T) {
+	m := NewTyped[int]()
+	for i := 0; i < 10; i++ {
+		m.Set(uint16(i), i)
+	}
+
+	snap := m.Snapshot()
+	visited := 0
+	snap.Range(func(key uint16, value int) bool {
+		visited++
+		return false
+	})
+	if visited != 1 {
+		t.Errorf("expected Range to stop after the first entry, got %d", visited)
+	}
+}
+
+func TestSnapshotIsolatedFromLaterWrites(t *testing.T) {
+	m := NewTyped[int]()
+	m.Set(uint16(1), 1)
+
+	snap := m.Snapshot()
+	m.Set(uint16(1), 2)
+	m.Set(uint16(2), 2)
+
+	if v, _ := snap.Get(uint16(1)); v != 1 {
+		t.Error("expected the snapshot to keep the value as of the time it was taken")
+	}
+	if _, ok := snap.Get(uint16(2)); ok {
+		t.Error("expected the snapshot not to see keys added after it was taken")
+	}
+	if snap.Len() != 1 {
+		t.Errorf("expected the snapshot to still have 1 entry, got %d", snap.Len())
+	}
+}
+
+func TestSnapshotMarshalJSON(t *testing.T) {
+	m := NewTyped[int]()
+	m.Set(uint16(1), 1)
+	m.Set(uint16(2), 2)
+
+	j, err := json.Marshal(m.Snapshot())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `{"1":1,"2":2}`
+	if string(j) != expected {
+		t.Errorf("got %s, expected %s", j, expected)
+	}
+}
+
+func TestSnapshotConcurrentWithWriters(t *testing.T) {
+	m := NewTyped[int]()
+	for i := 0; i < 100; i++ {
+		m.Set(uint16(i), i)
+	}
+
+	var wg sync.WaitGroup
+	stop := make(chan struct{})
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		i := 0
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+				m.Set(uint16(i%100), i)
+				i++
+			}
+		}
+	}()
+
+	for i := 0; i < 20; i++ {
+		snap := m.Snapshot()
+		if snap.Len() != 100 {
+			t.Errorf("expected every snapshot to have 100 entries, got %d", snap.Len())
+		}
+	}
+
+	close(stop)
+	wg.Wait()
+}